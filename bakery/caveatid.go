@@ -0,0 +1,85 @@
+package bakery
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// caveatInfo holds the information encrypted inside a third party
+// caveat id: the root key used to sign the discharge macaroon and the
+// condition that the discharger should check.
+type caveatInfo struct {
+	RootKey   []byte
+	Condition string
+}
+
+// caveatIdEnvelope holds a third party caveat id as given to a client. It
+// contains the public keys of both parties so that whichever key pair is
+// currently in use can be identified.
+type caveatIdEnvelope struct {
+	ThirdPartyPublicKey PublicKey
+	FirstPartyPublicKey PublicKey
+	Nonce               []byte
+	Id                  []byte
+}
+
+// encodeCaveatId encrypts rootKey and condition so that only the holder
+// of thirdPartyKey's private key can read them, and encodes the result,
+// along with the public keys involved, as a caveat id string.
+func encodeCaveatId(rootKey []byte, condition string, thirdPartyKey *PublicKey, firstPartyPublic *PublicKey, firstPartyPrivate *PrivateKey) (string, error) {
+	plain, err := json.Marshal(caveatInfo{
+		RootKey:   rootKey,
+		Condition: condition,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal caveat info: %v", err)
+	}
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", fmt.Errorf("cannot generate nonce: %v", err)
+	}
+	sealed := box.Seal(nil, plain, &nonce, (*[32]byte)(&thirdPartyKey.Key), (*[32]byte)(&firstPartyPrivate.Key))
+	env := caveatIdEnvelope{
+		ThirdPartyPublicKey: *thirdPartyKey,
+		FirstPartyPublicKey: *firstPartyPublic,
+		Nonce:               nonce[:],
+		Id:                  sealed,
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal caveat id: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCaveatId decrypts a caveat id created by encodeCaveatId using the
+// given private key, which must correspond to the envelope's
+// ThirdPartyPublicKey.
+func decodeCaveatId(id string, privateKey *PrivateKey) (*caveatInfo, error) {
+	data, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return nil, fmt.Errorf("cannot base64-decode caveat id: %v", err)
+	}
+	var env caveatIdEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal caveat id: %v", err)
+	}
+	if len(env.Nonce) != 24 {
+		return nil, fmt.Errorf("caveat id has invalid nonce length")
+	}
+	var nonce [24]byte
+	copy(nonce[:], env.Nonce)
+	plain, ok := box.Open(nil, env.Id, &nonce, (*[32]byte)(&env.FirstPartyPublicKey.Key), (*[32]byte)(&privateKey.Key))
+	if !ok {
+		return nil, fmt.Errorf("cannot decrypt caveat id: public/private key mismatch")
+	}
+	var info caveatInfo
+	if err := json.Unmarshal(plain, &info); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal decrypted caveat info: %v", err)
+	}
+	return &info, nil
+}
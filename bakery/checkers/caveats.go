@@ -0,0 +1,55 @@
+package checkers
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+)
+
+// TimeBefore returns a caveat that will check that the time that it is
+// checked is before t.
+func TimeBefore(t time.Time) bakery.Caveat {
+	return bakery.Caveat{
+		Condition: CondTimeBefore + " " + t.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// DeclaredCaveat returns a caveat that will check that the given key
+// has been declared (see RequestChecker.Declared) to have the given value.
+func DeclaredCaveat(key, value string) bakery.Caveat {
+	return Declared{Key: key, Value: value}.Caveat()
+}
+
+// AllowCaveat returns a caveat that will check that the operation
+// being authorized is one of ops.
+func AllowCaveat(ops ...string) bakery.Caveat {
+	return bakery.Caveat{
+		Condition: CondAllow + " " + strings.Join(ops, ","),
+	}
+}
+
+// DenyCaveat returns a caveat that will check that the operation being
+// authorized is none of ops.
+func DenyCaveat(ops ...string) bakery.Caveat {
+	return bakery.Caveat{
+		Condition: CondDeny + " " + strings.Join(ops, ","),
+	}
+}
+
+// OperationChecker returns a caveat that will check that the operation
+// being authorized is exactly op.
+func OperationChecker(op string) bakery.Caveat {
+	return bakery.Caveat{
+		Condition: CondOperation + " " + op,
+	}
+}
+
+// ClientIPAddrCaveat returns a caveat that will check that the
+// request's client IP address is addr.
+func ClientIPAddrCaveat(addr net.IP) bakery.Caveat {
+	return bakery.Caveat{
+		Condition: CondClientIPAddr + " " + addr.String(),
+	}
+}
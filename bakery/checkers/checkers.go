@@ -0,0 +1,113 @@
+// Package checkers provides a registry-based implementation of
+// bakery.FirstPartyChecker, along with a set of standard caveat
+// conditions and the convenience constructors used to create them.
+package checkers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckFunc is the type of function used to check a single caveat
+// condition against the contextual information held in a
+// RequestChecker. cond holds the condition itself (the word that the
+// function was registered against) and arg holds everything following
+// the first space in the caveat, if any.
+type CheckFunc func(ctx *RequestChecker, cond, arg string) error
+
+// Checker is a registry, built with New and Register, that dispatches
+// caveat conditions to registered handlers. It is safe to share between
+// concurrent requests once no more conditions are being registered on
+// it. It holds no per-request state: use NewRequestChecker to
+// obtain the bakery.FirstPartyChecker that actually checks a single
+// request's caveats.
+type Checker struct {
+	checkers map[string]CheckFunc
+}
+
+// New returns a Checker that knows about the standard caveat
+// conditions (see RegisterStd) together with any additional conditions
+// registered on the given extra checkers; where the same condition is
+// registered more than once, the last one given to New wins.
+func New(extra ...*Checker) *Checker {
+	c := &Checker{
+		checkers: make(map[string]CheckFunc),
+	}
+	c.RegisterStd()
+	for _, other := range extra {
+		for cond, f := range other.checkers {
+			c.checkers[cond] = f
+		}
+	}
+	return c
+}
+
+// Register associates the given condition word with a check function,
+// so that a caveat "cond rest-of-caveat" will be checked by calling
+// f(ctx, cond, "rest-of-caveat"). It is meant to be used while building
+// up a Checker with New, before it is shared between requests; it is
+// not safe to call concurrently with checks made via a RequestChecker
+// obtained from c.
+func (c *Checker) Register(cond string, f CheckFunc) {
+	c.checkers[cond] = f
+}
+
+// NewRequestChecker returns a bakery.FirstPartyChecker for a single
+// request, checking caveats against c together with the given
+// operation and client IP address. Unlike Checker, the returned
+// RequestChecker carries mutable per-request state and must not be
+// shared between concurrently-handled requests; call
+// NewRequestChecker afresh for each one.
+func (c *Checker) NewRequestChecker(operation, clientIPAddr string) *RequestChecker {
+	return &RequestChecker{
+		checker:      c,
+		Operation:    operation,
+		ClientIPAddr: clientIPAddr,
+	}
+}
+
+// RequestChecker is the per-request bakery.FirstPartyChecker returned
+// by Checker.NewRequestChecker. It holds the contextual information
+// that the standard checkers need - the operation being authorized,
+// the client's address, and any declared key/value pairs - and should
+// be discarded once the request it was created for has been checked.
+type RequestChecker struct {
+	checker *Checker
+
+	// Operation and ClientIPAddr hold the context against which the
+	// standard "operation"/"allow"/"deny" and "client-ip-addr"
+	// caveats are checked.
+	Operation    string
+	ClientIPAddr string
+
+	// Declared holds the key/value pairs known to be true for the
+	// current request, as checked by the standard "declared" caveat.
+	// It is populated by SetMacaroons, usually called automatically
+	// by bakery.Request.Check via bakery.ContextChecker.
+	Declared map[string]string
+}
+
+// CheckFirstPartyCaveat implements bakery.FirstPartyChecker by parsing
+// caveat into a condition and an argument, and dispatching it to
+// whatever check function was registered for that condition on rc's
+// Checker.
+func (rc *RequestChecker) CheckFirstPartyCaveat(caveat string) error {
+	cond, arg := ParseCaveat(caveat)
+	check, ok := rc.checker.checkers[cond]
+	if !ok {
+		return fmt.Errorf("caveat %q not satisfied: unrecognized caveat", caveat)
+	}
+	return check(rc, cond, arg)
+}
+
+// ParseCaveat splits a caveat condition into the leading word (cond)
+// and the remainder of the string (arg), which is empty if the caveat
+// has no argument. For example, ParseCaveat("time-before 2015-01-01T00:00:00Z")
+// returns ("time-before", "2015-01-01T00:00:00Z").
+func ParseCaveat(caveat string) (cond, arg string) {
+	parts := strings.SplitN(caveat, " ", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
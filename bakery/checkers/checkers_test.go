@@ -0,0 +1,93 @@
+package checkers_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/macaroon-bakery.v0/bakery/checkers"
+)
+
+func mustParseIP(s string) net.IP {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		panic("invalid IP address " + s)
+	}
+	return ip
+}
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type CheckersSuite struct{}
+
+var _ = gc.Suite(&CheckersSuite{})
+
+func (s *CheckersSuite) TestParseCaveat(c *gc.C) {
+	cond, arg := checkers.ParseCaveat("time-before 2015-01-01T00:00:00Z")
+	c.Assert(cond, gc.Equals, "time-before")
+	c.Assert(arg, gc.Equals, "2015-01-01T00:00:00Z")
+
+	cond, arg = checkers.ParseCaveat("allow")
+	c.Assert(cond, gc.Equals, "allow")
+	c.Assert(arg, gc.Equals, "")
+}
+
+func (s *CheckersSuite) TestUnrecognizedCondition(c *gc.C) {
+	rc := checkers.New().NewRequestChecker("op", "")
+	err := rc.CheckFirstPartyCaveat("unknown-cond something")
+	c.Assert(err, gc.ErrorMatches, `caveat "unknown-cond something" not satisfied: unrecognized caveat`)
+}
+
+func (s *CheckersSuite) TestTimeBefore(c *gc.C) {
+	rc := checkers.New().NewRequestChecker("", "")
+	future := time.Now().Add(time.Hour)
+	c.Assert(rc.CheckFirstPartyCaveat(checkers.TimeBefore(future).Condition), gc.IsNil)
+
+	past := time.Now().Add(-time.Hour)
+	err := rc.CheckFirstPartyCaveat(checkers.TimeBefore(past).Condition)
+	c.Assert(err, gc.ErrorMatches, `caveat "time-before .*" not satisfied: macaroon has expired`)
+}
+
+func (s *CheckersSuite) TestOperation(c *gc.C) {
+	rc := checkers.New().NewRequestChecker("read", "")
+	c.Assert(rc.CheckFirstPartyCaveat(checkers.OperationChecker("read").Condition), gc.IsNil)
+	err := rc.CheckFirstPartyCaveat(checkers.OperationChecker("write").Condition)
+	c.Assert(err, gc.ErrorMatches, `caveat "operation write" not satisfied: operation is "read", expected "write"`)
+}
+
+func (s *CheckersSuite) TestAllowAndDeny(c *gc.C) {
+	rc := checkers.New().NewRequestChecker("read", "")
+	c.Assert(rc.CheckFirstPartyCaveat(checkers.AllowCaveat("read", "write").Condition), gc.IsNil)
+	err := rc.CheckFirstPartyCaveat(checkers.AllowCaveat("write").Condition)
+	c.Assert(err, gc.ErrorMatches, `caveat "allow write" not satisfied: operation "read" not allowed`)
+
+	c.Assert(rc.CheckFirstPartyCaveat(checkers.DenyCaveat("write").Condition), gc.IsNil)
+	err = rc.CheckFirstPartyCaveat(checkers.DenyCaveat("read").Condition)
+	c.Assert(err, gc.ErrorMatches, `caveat "deny read" not satisfied: operation "read" forbidden`)
+}
+
+func (s *CheckersSuite) TestClientIPAddr(c *gc.C) {
+	rc := checkers.New().NewRequestChecker("", "10.0.0.1")
+	c.Assert(rc.CheckFirstPartyCaveat(checkers.ClientIPAddrCaveat(mustParseIP("10.0.0.1")).Condition), gc.IsNil)
+	err := rc.CheckFirstPartyCaveat(checkers.ClientIPAddrCaveat(mustParseIP("10.0.0.2")).Condition)
+	c.Assert(err, gc.ErrorMatches, `caveat "client-ip-addr 10.0.0.2" not satisfied: client IP address is "10.0.0.1", expected "10.0.0.2"`)
+}
+
+// TestRequestCheckersAreIndependent checks the fix for the race where
+// Operation, ClientIPAddr and Declared were mutable fields on a
+// Checker shared between requests: two RequestCheckers created from
+// the same, shared Checker must not see each other's context.
+func (s *CheckersSuite) TestRequestCheckersAreIndependent(c *gc.C) {
+	shared := checkers.New()
+	rc1 := shared.NewRequestChecker("read", "10.0.0.1")
+	rc2 := shared.NewRequestChecker("write", "10.0.0.2")
+
+	c.Assert(rc1.CheckFirstPartyCaveat(checkers.OperationChecker("read").Condition), gc.IsNil)
+	c.Assert(rc2.CheckFirstPartyCaveat(checkers.OperationChecker("write").Condition), gc.IsNil)
+	c.Assert(rc1.CheckFirstPartyCaveat(checkers.OperationChecker("write").Condition), gc.NotNil)
+	c.Assert(rc2.CheckFirstPartyCaveat(checkers.OperationChecker("read").Condition), gc.NotNil)
+}
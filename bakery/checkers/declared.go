@@ -0,0 +1,74 @@
+package checkers
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+	"gopkg.in/macaroon.v1"
+)
+
+// Declared represents a "declared" first party caveat, associating a
+// key with a value that the caveat's issuer asserts to be true. It is
+// typically used by a discharge macaroon to carry an identity
+// assertion (for example a username) back to the service that
+// requested the discharge.
+type Declared struct {
+	Key   string
+	Value string
+}
+
+// Caveat returns the bakery.Caveat form of d.
+func (d Declared) Caveat() bakery.Caveat {
+	return bakery.Caveat{
+		Condition: fmt.Sprintf("%s %s %s", CondDeclared, d.Key, d.Value),
+	}
+}
+
+// SetMacaroons implements bakery.ContextChecker.SetMacaroons. It
+// infers the key/value pairs declared across ms with InferDeclared and
+// makes them available to the "declared" caveat checker as
+// rc.Declared.
+func (rc *RequestChecker) SetMacaroons(ms []*macaroon.Macaroon) {
+	rc.Declared = InferDeclared(ms)
+}
+
+// InferDeclared returns the key/value pairs declared by "declared key
+// value" first party caveats found across all of macaroons - typically
+// a primary macaroon together with its discharges. If the same key is
+// declared with conflicting values anywhere in the set, it is omitted
+// from the result entirely, since neither value can be trusted.
+func InferDeclared(macaroons []*macaroon.Macaroon) map[string]string {
+	declared := make(map[string]string)
+	conflicted := make(map[string]bool)
+	for _, m := range macaroons {
+		if m == nil {
+			continue
+		}
+		for _, cav := range m.Caveats() {
+			if cav.Location != "" {
+				// Not a first party caveat.
+				continue
+			}
+			cond, arg := ParseCaveat(cav.Id)
+			if cond != CondDeclared {
+				continue
+			}
+			parts := strings.SplitN(arg, " ", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			key, value := parts[0], parts[1]
+			if conflicted[key] {
+				continue
+			}
+			if old, ok := declared[key]; ok && old != value {
+				conflicted[key] = true
+				delete(declared, key)
+				continue
+			}
+			declared[key] = value
+		}
+	}
+	return declared
+}
@@ -0,0 +1,77 @@
+package checkers_test
+
+import (
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/macaroon-bakery.v0/bakery/checkers"
+	"gopkg.in/macaroon.v1"
+)
+
+type DeclaredSuite struct{}
+
+var _ = gc.Suite(&DeclaredSuite{})
+
+func (s *DeclaredSuite) TestInferDeclaredSingleMacaroon(c *gc.C) {
+	m, err := macaroon.New([]byte("key"), "id", "loc")
+	c.Assert(err, gc.IsNil)
+	m.AddFirstPartyCaveat(checkers.DeclaredCaveat("user", "bob").Condition)
+
+	declared := checkers.InferDeclared([]*macaroon.Macaroon{m})
+	c.Assert(declared, gc.DeepEquals, map[string]string{"user": "bob"})
+}
+
+func (s *DeclaredSuite) TestInferDeclaredAcrossMacaroons(c *gc.C) {
+	m1, err := macaroon.New([]byte("key1"), "id1", "loc")
+	c.Assert(err, gc.IsNil)
+	m1.AddFirstPartyCaveat(checkers.DeclaredCaveat("user", "bob").Condition)
+
+	m2, err := macaroon.New([]byte("key2"), "id2", "loc")
+	c.Assert(err, gc.IsNil)
+	m2.AddFirstPartyCaveat(checkers.DeclaredCaveat("group", "admin").Condition)
+
+	declared := checkers.InferDeclared([]*macaroon.Macaroon{m1, m2})
+	c.Assert(declared, gc.DeepEquals, map[string]string{"user": "bob", "group": "admin"})
+}
+
+func (s *DeclaredSuite) TestInferDeclaredConflictIsOmitted(c *gc.C) {
+	m1, err := macaroon.New([]byte("key1"), "id1", "loc")
+	c.Assert(err, gc.IsNil)
+	m1.AddFirstPartyCaveat(checkers.DeclaredCaveat("user", "bob").Condition)
+
+	m2, err := macaroon.New([]byte("key2"), "id2", "loc")
+	c.Assert(err, gc.IsNil)
+	m2.AddFirstPartyCaveat(checkers.DeclaredCaveat("user", "alice").Condition)
+
+	declared := checkers.InferDeclared([]*macaroon.Macaroon{m1, m2})
+	c.Assert(declared, gc.DeepEquals, map[string]string{})
+}
+
+func (s *DeclaredSuite) TestInferDeclaredIgnoresThirdPartyCaveats(c *gc.C) {
+	m, err := macaroon.New([]byte("key"), "id", "loc")
+	c.Assert(err, gc.IsNil)
+	err = m.AddThirdPartyCaveat([]byte("third-party-key"), "declared user bob", "as-loc")
+	c.Assert(err, gc.IsNil)
+
+	declared := checkers.InferDeclared([]*macaroon.Macaroon{m})
+	c.Assert(declared, gc.DeepEquals, map[string]string{})
+}
+
+func (s *DeclaredSuite) TestInferDeclaredSkipsNilMacaroons(c *gc.C) {
+	declared := checkers.InferDeclared([]*macaroon.Macaroon{nil})
+	c.Assert(declared, gc.DeepEquals, map[string]string{})
+}
+
+// TestSetMacaroonsPopulatesPerRequestDeclared checks the fix for the
+// race where SetMacaroons mutated a Checker shared between requests:
+// it now populates the Declared field of the per-request
+// RequestChecker returned by NewRequestChecker instead.
+func (s *DeclaredSuite) TestSetMacaroonsPopulatesPerRequestDeclared(c *gc.C) {
+	m, err := macaroon.New([]byte("key"), "id", "loc")
+	c.Assert(err, gc.IsNil)
+	m.AddFirstPartyCaveat(checkers.DeclaredCaveat("user", "bob").Condition)
+
+	rc := checkers.New().NewRequestChecker("", "")
+	rc.SetMacaroons([]*macaroon.Macaroon{m})
+	c.Assert(rc.Declared, gc.DeepEquals, map[string]string{"user": "bob"})
+	c.Assert(rc.CheckFirstPartyCaveat(checkers.DeclaredCaveat("user", "bob").Condition), gc.IsNil)
+}
@@ -0,0 +1,88 @@
+package checkers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Condition names for the standard checkers registered by RegisterStd.
+const (
+	CondTimeBefore   = "time-before"
+	CondDeclared     = "declared"
+	CondAllow        = "allow"
+	CondDeny         = "deny"
+	CondClientIPAddr = "client-ip-addr"
+	CondOperation    = "operation"
+)
+
+// RegisterStd registers the standard caveat conditions on c: time-before,
+// declared, allow, deny, client-ip-addr and operation.
+func (c *Checker) RegisterStd() {
+	c.Register(CondTimeBefore, checkTimeBefore)
+	c.Register(CondDeclared, checkDeclared)
+	c.Register(CondAllow, checkAllow)
+	c.Register(CondDeny, checkDeny)
+	c.Register(CondClientIPAddr, checkClientIPAddr)
+	c.Register(CondOperation, checkOperation)
+}
+
+func checkTimeBefore(_ *RequestChecker, _, arg string) error {
+	t, err := time.Parse(time.RFC3339Nano, arg)
+	if err != nil {
+		return fmt.Errorf("caveat %q not satisfied: %v", CondTimeBefore+" "+arg, err)
+	}
+	if !time.Now().Before(t) {
+		return fmt.Errorf("caveat %q not satisfied: macaroon has expired", CondTimeBefore+" "+arg)
+	}
+	return nil
+}
+
+func checkDeclared(ctx *RequestChecker, _, arg string) error {
+	parts := strings.SplitN(arg, " ", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("caveat %q not satisfied: malformed declared caveat", CondDeclared+" "+arg)
+	}
+	key, value := parts[0], parts[1]
+	if ctx.Declared[key] != value {
+		return fmt.Errorf("caveat %q not satisfied: got %s=%q, expected %q", CondDeclared+" "+arg, key, ctx.Declared[key], value)
+	}
+	return nil
+}
+
+func checkAllow(ctx *RequestChecker, _, arg string) error {
+	if !containsOp(strings.Split(arg, ","), ctx.Operation) {
+		return fmt.Errorf("caveat %q not satisfied: operation %q not allowed", CondAllow+" "+arg, ctx.Operation)
+	}
+	return nil
+}
+
+func checkDeny(ctx *RequestChecker, _, arg string) error {
+	if containsOp(strings.Split(arg, ","), ctx.Operation) {
+		return fmt.Errorf("caveat %q not satisfied: operation %q forbidden", CondDeny+" "+arg, ctx.Operation)
+	}
+	return nil
+}
+
+func checkOperation(ctx *RequestChecker, _, arg string) error {
+	if ctx.Operation != arg {
+		return fmt.Errorf("caveat %q not satisfied: operation is %q, expected %q", CondOperation+" "+arg, ctx.Operation, arg)
+	}
+	return nil
+}
+
+func checkClientIPAddr(ctx *RequestChecker, _, arg string) error {
+	if ctx.ClientIPAddr != arg {
+		return fmt.Errorf("caveat %q not satisfied: client IP address is %q, expected %q", CondClientIPAddr+" "+arg, ctx.ClientIPAddr, arg)
+	}
+	return nil
+}
+
+func containsOp(ops []string, op string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package bakery
+
+import (
+	"fmt"
+
+	"gopkg.in/macaroon.v1"
+)
+
+// Discharge creates a discharge macaroon for the third party caveat
+// identified by caveatId, previously added to some other macaroon with
+// AddCaveat. The given checker is used to check that the condition
+// encoded in the caveat, once decrypted, is satisfied; any caveats it
+// returns are added to the discharge macaroon.
+func (svc *Service) Discharge(checker ThirdPartyChecker, caveatId string) (*macaroon.Macaroon, error) {
+	info, err := decodeCaveatId(caveatId, &svc.key.Private)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode caveat id: %v", err)
+	}
+	caveats, err := checker.CheckThirdPartyCaveat(caveatId, info.Condition)
+	if err != nil {
+		return nil, err
+	}
+	m, err := macaroon.New(info.RootKey, caveatId, svc.location)
+	if err != nil {
+		return nil, fmt.Errorf("cannot bake discharge macaroon: %v", err)
+	}
+	for _, cav := range caveats {
+		if err := svc.AddCaveat(m, cav); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// DischargeAll obtains discharge macaroons for all the third party
+// caveats in m, recursively, and returns them. It calls getDischarge,
+// passing the location of the macaroon that required the discharge (the
+// first argument) and the caveat itself, to find the discharge
+// macaroons.
+func DischargeAll(m *macaroon.Macaroon, getDischarge func(firstPartyLocation string, cav macaroon.Caveat) (*macaroon.Macaroon, error)) ([]*macaroon.Macaroon, error) {
+	var discharges []*macaroon.Macaroon
+	var addCaveats func(loc string, caveats []macaroon.Caveat) error
+	addCaveats = func(loc string, caveats []macaroon.Caveat) error {
+		for _, cav := range caveats {
+			if cav.Location == "" {
+				continue
+			}
+			dm, err := getDischarge(loc, cav)
+			if err != nil {
+				return fmt.Errorf("cannot get discharge for caveat addressed to %q: %v", cav.Location, err)
+			}
+			dm.Bind(m.Signature())
+			discharges = append(discharges, dm)
+			if err := addCaveats(dm.Location(), dm.Caveats()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := addCaveats(m.Location(), m.Caveats()); err != nil {
+		return nil, err
+	}
+	return discharges, nil
+}
@@ -0,0 +1,73 @@
+package bakery
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// KeyLen is the length in bytes of the public and private keys used to
+// encrypt third party caveat ids.
+const KeyLen = 32
+
+// PublicKey is a NaCl public key that can be used to encrypt third party
+// caveats addressed to the holder of the corresponding PrivateKey.
+type PublicKey struct {
+	Key
+}
+
+// PrivateKey is a NaCl private key that can be used to decrypt third party
+// caveats addressed to the holder's PublicKey.
+type PrivateKey struct {
+	Key
+}
+
+// Key holds a NaCl box key.
+type Key [KeyLen]byte
+
+// KeyPair holds a public/private key pair generated by GenerateKey.
+type KeyPair struct {
+	Public  PublicKey
+	Private PrivateKey
+}
+
+// GenerateKey generates a new KeyPair.
+func GenerateKey() (*KeyPair, error) {
+	var key KeyPair
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate key: %v", err)
+	}
+	key.Public.Key = Key(*pub)
+	key.Private.Key = Key(*priv)
+	return &key, nil
+}
+
+// String returns the base64 representation of the key.
+func (k Key) String() string {
+	data, _ := k.MarshalText()
+	return string(data)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k Key) MarshalText() ([]byte, error) {
+	data := make([]byte, base64.StdEncoding.EncodedLen(len(k)))
+	base64.StdEncoding.Encode(data, k[:])
+	return data, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *Key) UnmarshalText(text []byte) error {
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(data, text)
+	if err != nil {
+		return fmt.Errorf("cannot decode base64 key: %v", err)
+	}
+	if n != KeyLen {
+		return fmt.Errorf("key has unexpected length %d", n)
+	}
+	copy(k[:], data[:n])
+	return nil
+}
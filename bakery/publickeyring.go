@@ -0,0 +1,65 @@
+package bakery
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PublicKeyRing is a PublicKeyLocator that holds an explicit set of
+// location/key associations, added with AddPublicKeyForLocation. It
+// replaces a static PublicKeyLocatorMap when the set of known
+// dischargers grows or is not known in advance, and supports prefix
+// entries so that a single key can cover a whole location namespace.
+type PublicKeyRing struct {
+	mu      sync.RWMutex
+	entries []publicKeyRingEntry
+}
+
+type publicKeyRingEntry struct {
+	location string
+	prefix   bool
+	key      *PublicKey
+}
+
+// NewPublicKeyRing returns a new, empty PublicKeyRing.
+func NewPublicKeyRing() *PublicKeyRing {
+	return &PublicKeyRing{}
+}
+
+// AddPublicKeyForLocation associates key with loc. If prefix is true,
+// key will also be returned for any location that has loc as a prefix,
+// unless a more specific entry matches it instead.
+func (r *PublicKeyRing) AddPublicKeyForLocation(loc string, prefix bool, key *PublicKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, publicKeyRingEntry{
+		location: loc,
+		prefix:   prefix,
+		key:      key,
+	})
+}
+
+// PublicKeyForLocation implements PublicKeyLocator.PublicKeyForLocation.
+// An exact match always wins; otherwise the longest matching prefix
+// entry is used.
+func (r *PublicKeyRing) PublicKeyForLocation(loc string) (*PublicKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var best *publicKeyRingEntry
+	for i, e := range r.entries {
+		if !e.prefix {
+			if e.location == loc {
+				return e.key, nil
+			}
+			continue
+		}
+		if strings.HasPrefix(loc, e.location) && (best == nil || len(e.location) > len(best.location)) {
+			best = &r.entries[i]
+		}
+	}
+	if best != nil {
+		return best.key, nil
+	}
+	return nil, fmt.Errorf("public key for location %q not found", loc)
+}
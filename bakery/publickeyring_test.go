@@ -0,0 +1,78 @@
+package bakery_test
+
+import (
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+)
+
+type PublicKeyRingSuite struct{}
+
+var _ = gc.Suite(&PublicKeyRingSuite{})
+
+func (s *PublicKeyRingSuite) TestExactMatch(c *gc.C) {
+	k1, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+	k2, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+
+	ring := bakery.NewPublicKeyRing()
+	ring.AddPublicKeyForLocation("loc1", false, &k1.Public)
+	ring.AddPublicKeyForLocation("loc2", false, &k2.Public)
+
+	got, err := ring.PublicKeyForLocation("loc1")
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.Equals, &k1.Public)
+
+	got, err = ring.PublicKeyForLocation("loc2")
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.Equals, &k2.Public)
+}
+
+func (s *PublicKeyRingSuite) TestNotFound(c *gc.C) {
+	ring := bakery.NewPublicKeyRing()
+	_, err := ring.PublicKeyForLocation("unknown")
+	c.Assert(err, gc.ErrorMatches, `public key for location "unknown" not found`)
+}
+
+func (s *PublicKeyRingSuite) TestPrefixMatch(c *gc.C) {
+	k, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+
+	ring := bakery.NewPublicKeyRing()
+	ring.AddPublicKeyForLocation("https://example.com/", true, &k.Public)
+
+	got, err := ring.PublicKeyForLocation("https://example.com/discharger")
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.Equals, &k.Public)
+}
+
+func (s *PublicKeyRingSuite) TestLongestPrefixWins(c *gc.C) {
+	kShort, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+	kLong, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+
+	ring := bakery.NewPublicKeyRing()
+	ring.AddPublicKeyForLocation("https://example.com/", true, &kShort.Public)
+	ring.AddPublicKeyForLocation("https://example.com/team/", true, &kLong.Public)
+
+	got, err := ring.PublicKeyForLocation("https://example.com/team/discharger")
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.Equals, &kLong.Public)
+}
+
+func (s *PublicKeyRingSuite) TestExactMatchWinsOverPrefix(c *gc.C) {
+	kPrefix, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+	kExact, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+
+	ring := bakery.NewPublicKeyRing()
+	ring.AddPublicKeyForLocation("https://example.com/", true, &kPrefix.Public)
+	ring.AddPublicKeyForLocation("https://example.com/discharger", false, &kExact.Public)
+
+	got, err := ring.PublicKeyForLocation("https://example.com/discharger")
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.Equals, &kExact.Public)
+}
@@ -0,0 +1,72 @@
+package bakery
+
+import (
+	"fmt"
+
+	"gopkg.in/macaroon.v1"
+)
+
+// Request represents a request made to a service that may be
+// authorized by one or more macaroons.
+type Request struct {
+	svc       *Service
+	checker   FirstPartyChecker
+	macaroons []*macaroon.Macaroon
+}
+
+// ContextChecker may optionally be implemented by a FirstPartyChecker
+// that needs to know about all the macaroons presented with a request,
+// rather than just the single caveat condition it is asked to check at
+// a time - for example, one that needs to infer the key/value pairs
+// declared by "declared" caveats across the primary macaroon and its
+// discharges. If a Request's checker implements it, Check calls
+// SetMacaroons with the full set of macaroons before checking any of
+// their caveats.
+type ContextChecker interface {
+	FirstPartyChecker
+	SetMacaroons(macaroons []*macaroon.Macaroon)
+}
+
+// NewRequest returns a new request that will check caveats against
+// checker, using svc to look up the root keys of any macaroons added to
+// it.
+func (svc *Service) NewRequest(checker FirstPartyChecker) *Request {
+	return &Request{
+		svc:     svc,
+		checker: checker,
+	}
+}
+
+// AddClientMacaroon associates the given macaroon with the request. The
+// first macaroon added is treated as the primary ("client") macaroon;
+// any subsequent macaroons are treated as discharges for its third
+// party caveats.
+func (req *Request) AddClientMacaroon(m *macaroon.Macaroon) {
+	req.macaroons = append(req.macaroons, m)
+}
+
+// Check checks that the macaroons added to the request are valid and
+// satisfy all their caveats, first party caveats being checked with the
+// request's FirstPartyChecker and third party caveats being satisfied by
+// the discharge macaroons also added to the request.
+func (req *Request) Check() error {
+	if len(req.macaroons) == 0 {
+		return fmt.Errorf("verification failed: no macaroon presented")
+	}
+	primary := req.macaroons[0]
+	discharges := req.macaroons[1:]
+
+	if cc, ok := req.checker.(ContextChecker); ok {
+		cc.SetMacaroons(req.macaroons)
+	}
+
+	rootKey, err := req.svc.store.Get(primary.Id())
+	if err != nil {
+		return fmt.Errorf("verification failed: cannot find root key for macaroon: %v", err)
+	}
+	err = primary.Verify([]byte(rootKey), req.checker.CheckFirstPartyCaveat, discharges)
+	if err != nil {
+		return fmt.Errorf("verification failed: %v", err)
+	}
+	return nil
+}
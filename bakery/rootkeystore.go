@@ -0,0 +1,142 @@
+package bakery
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by RootKeyStore implementations to signify
+// that the given root key id was not found, either because it was
+// never generated or because it has expired.
+var ErrNotFound = fmt.Errorf("root key not found")
+
+// RootKeyStore defines storage for the macaroon root keys used by a
+// Service. Unlike a plain key-value store, a RootKeyStore is
+// responsible for generating its own keys and ids, and for expiring
+// them over time, so that a Service need never reuse a compromised or
+// stale key.
+type RootKeyStore interface {
+	// RootKey returns the root key to use for minting a new
+	// macaroon, and an id that can later be passed to Get to
+	// retrieve it again. It may return the same key and id on
+	// successive calls, according to the store's generate interval.
+	RootKey() (key []byte, id string, err error)
+
+	// Get returns the root key associated with id, as previously
+	// returned from RootKey. It returns ErrNotFound if the key has
+	// expired or was never generated by this store.
+	Get(id string) (key []byte, err error)
+}
+
+const (
+	// DefaultExpireAfter is used as the expiry for generated root
+	// keys when a store is created with a zero ExpireAfter.
+	DefaultExpireAfter = 24 * time.Hour
+
+	// DefaultGenerateInterval is used as the generate interval for
+	// root keys when a store is created with a zero
+	// GenerateInterval.
+	DefaultGenerateInterval = time.Hour
+)
+
+// rootKeyEntry holds a single generated root key together with its
+// creation time, which is used to decide when the key has expired.
+type rootKeyEntry struct {
+	RootKey []byte
+	Created time.Time
+}
+
+func (e rootKeyEntry) expired(expireAfter time.Duration, now time.Time) bool {
+	return now.Sub(e.Created) > expireAfter
+}
+
+// newRootKeyId returns a new random, base64-encoded root key id.
+func newRootKeyId() (string, error) {
+	data := make([]byte, 24)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("cannot generate root key id: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// newRootKey returns a new random 24-byte root key.
+func newRootKey() ([]byte, error) {
+	key := make([]byte, 24)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("cannot generate root key: %v", err)
+	}
+	return key, nil
+}
+
+// MemRootKeyStore returns a RootKeyStore that keeps its keys in memory.
+// It generates a new key whenever RootKey is called more than
+// generateInterval after the previous one, and Get rejects any key
+// older than expireAfter. A zero duration selects the corresponding
+// default.
+func MemRootKeyStore(expireAfter, generateInterval time.Duration) RootKeyStore {
+	if expireAfter == 0 {
+		expireAfter = DefaultExpireAfter
+	}
+	if generateInterval == 0 {
+		generateInterval = DefaultGenerateInterval
+	}
+	return &memRootKeyStore{
+		expireAfter:      expireAfter,
+		generateInterval: generateInterval,
+		keys:             make(map[string]rootKeyEntry),
+	}
+}
+
+type memRootKeyStore struct {
+	mu               sync.Mutex
+	expireAfter      time.Duration
+	generateInterval time.Duration
+	keys             map[string]rootKeyEntry
+	currentId        string
+	currentCreated   time.Time
+}
+
+// RootKey implements RootKeyStore.RootKey.
+func (s *memRootKeyStore) RootKey() ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.currentId != "" && now.Sub(s.currentCreated) < s.generateInterval {
+		return s.keys[s.currentId].RootKey, s.currentId, nil
+	}
+	key, err := newRootKey()
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := newRootKeyId()
+	if err != nil {
+		return nil, "", err
+	}
+	s.keys[id] = rootKeyEntry{RootKey: key, Created: now}
+	s.currentId, s.currentCreated = id, now
+	s.expireOldKeys(now)
+	return key, id, nil
+}
+
+// Get implements RootKeyStore.Get.
+func (s *memRootKeyStore) Get(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.keys[id]
+	if !ok || entry.expired(s.expireAfter, time.Now()) {
+		return nil, ErrNotFound
+	}
+	return entry.RootKey, nil
+}
+
+// expireOldKeys removes keys that have expired. Callers must hold s.mu.
+func (s *memRootKeyStore) expireOldKeys(now time.Time) {
+	for id, entry := range s.keys {
+		if entry.expired(s.expireAfter, now) {
+			delete(s.keys, id)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package bakery
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// rootKeyBucket is the bolt bucket in which BoltRootKeyStore keeps its
+// root key entries, keyed by root key id.
+var rootKeyBucket = []byte("macaroon-root-keys")
+
+// BoltRootKeyStore is a RootKeyStore that persists its keys in a bolt
+// database, so that keys (and therefore the ability to verify
+// previously minted macaroons) survive a process restart. It follows
+// the same generate/expire key lifecycle as lnd's macaroon service:
+// a fresh key is minted every generateInterval, and old keys stop
+// verifying once they are older than expireAfter.
+type BoltRootKeyStore struct {
+	db               *bolt.DB
+	expireAfter      time.Duration
+	generateInterval time.Duration
+
+	mu             sync.Mutex
+	currentId      string
+	currentCreated time.Time
+}
+
+// NewBoltRootKeyStore returns a BoltRootKeyStore that stores its keys
+// in db. A zero expireAfter or generateInterval selects the
+// corresponding default.
+func NewBoltRootKeyStore(db *bolt.DB, expireAfter, generateInterval time.Duration) (*BoltRootKeyStore, error) {
+	if expireAfter == 0 {
+		expireAfter = DefaultExpireAfter
+	}
+	if generateInterval == 0 {
+		generateInterval = DefaultGenerateInterval
+	}
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(rootKeyBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create root key bucket: %v", err)
+	}
+	return &BoltRootKeyStore{
+		db:               db,
+		expireAfter:      expireAfter,
+		generateInterval: generateInterval,
+	}, nil
+}
+
+// RootKey implements RootKeyStore.RootKey.
+func (s *BoltRootKeyStore) RootKey() ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.currentId != "" && now.Sub(s.currentCreated) < s.generateInterval {
+		if key, err := s.get(s.currentId); err == nil {
+			return key, s.currentId, nil
+		}
+	}
+	key, err := newRootKey()
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := newRootKeyId()
+	if err != nil {
+		return nil, "", err
+	}
+	entry := rootKeyEntry{RootKey: key, Created: now}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot marshal root key entry: %v", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(rootKeyBucket).Put([]byte(id), data)
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot store root key: %v", err)
+	}
+	s.currentId, s.currentCreated = id, now
+	s.expireOldKeys(now)
+	return key, id, nil
+}
+
+// Get implements RootKeyStore.Get.
+func (s *BoltRootKeyStore) Get(id string) ([]byte, error) {
+	return s.get(id)
+}
+
+func (s *BoltRootKeyStore) get(id string) ([]byte, error) {
+	entry, err := s.getEntry(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.expired(s.expireAfter, time.Now()) {
+		return nil, ErrNotFound
+	}
+	return entry.RootKey, nil
+}
+
+func (s *BoltRootKeyStore) getEntry(id string) (rootKeyEntry, error) {
+	var entry rootKeyEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(rootKeyBucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil {
+		return rootKeyEntry{}, err
+	}
+	return entry, nil
+}
+
+// expireOldKeys deletes keys from the database that have expired.
+// Callers must hold s.mu.
+func (s *BoltRootKeyStore) expireOldKeys(now time.Time) {
+	s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(rootKeyBucket)
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var entry rootKeyEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return nil
+			}
+			if entry.expired(s.expireAfter, now) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,131 @@
+package bakery_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type RootKeyStoreSuite struct{}
+
+var _ = gc.Suite(&RootKeyStoreSuite{})
+
+func (s *RootKeyStoreSuite) TestMemRootKeyStoreGeneratesAndReturnsKey(c *gc.C) {
+	store := bakery.MemRootKeyStore(0, 0)
+	key, id, err := store.RootKey()
+	c.Assert(err, gc.IsNil)
+	c.Assert(key, gc.HasLen, 24)
+	c.Assert(id, gc.Not(gc.Equals), "")
+
+	got, err := store.Get(id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.DeepEquals, key)
+}
+
+func (s *RootKeyStoreSuite) TestMemRootKeyStoreReusesKeyWithinGenerateInterval(c *gc.C) {
+	store := bakery.MemRootKeyStore(time.Hour, time.Hour)
+	key1, id1, err := store.RootKey()
+	c.Assert(err, gc.IsNil)
+	key2, id2, err := store.RootKey()
+	c.Assert(err, gc.IsNil)
+	c.Assert(id2, gc.Equals, id1)
+	c.Assert(key2, gc.DeepEquals, key1)
+}
+
+func (s *RootKeyStoreSuite) TestMemRootKeyStoreRotatesAfterGenerateInterval(c *gc.C) {
+	store := bakery.MemRootKeyStore(time.Hour, time.Millisecond)
+	_, id1, err := store.RootKey()
+	c.Assert(err, gc.IsNil)
+	time.Sleep(5 * time.Millisecond)
+	_, id2, err := store.RootKey()
+	c.Assert(err, gc.IsNil)
+	c.Assert(id2, gc.Not(gc.Equals), id1)
+}
+
+func (s *RootKeyStoreSuite) TestMemRootKeyStoreExpiresOldKeys(c *gc.C) {
+	store := bakery.MemRootKeyStore(time.Millisecond, time.Millisecond)
+	_, id, err := store.RootKey()
+	c.Assert(err, gc.IsNil)
+	time.Sleep(5 * time.Millisecond)
+	_, err = store.Get(id)
+	c.Assert(err, gc.Equals, bakery.ErrNotFound)
+}
+
+func (s *RootKeyStoreSuite) TestMemRootKeyStoreGetUnknownId(c *gc.C) {
+	store := bakery.MemRootKeyStore(0, 0)
+	_, err := store.Get("unknown")
+	c.Assert(err, gc.Equals, bakery.ErrNotFound)
+}
+
+func (s *RootKeyStoreSuite) TestBoltRootKeyStoreRoundTrip(c *gc.C) {
+	dir, err := ioutil.TempDir("", "bakery-test")
+	c.Assert(err, gc.IsNil)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, "keys.db"), 0600, nil)
+	c.Assert(err, gc.IsNil)
+	defer db.Close()
+
+	store, err := bakery.NewBoltRootKeyStore(db, 0, 0)
+	c.Assert(err, gc.IsNil)
+
+	key, id, err := store.RootKey()
+	c.Assert(err, gc.IsNil)
+	got, err := store.Get(id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.DeepEquals, key)
+}
+
+func (s *RootKeyStoreSuite) TestBoltRootKeyStoreExpiresOldKeys(c *gc.C) {
+	dir, err := ioutil.TempDir("", "bakery-test")
+	c.Assert(err, gc.IsNil)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, "keys.db"), 0600, nil)
+	c.Assert(err, gc.IsNil)
+	defer db.Close()
+
+	store, err := bakery.NewBoltRootKeyStore(db, time.Millisecond, time.Millisecond)
+	c.Assert(err, gc.IsNil)
+
+	_, id, err := store.RootKey()
+	c.Assert(err, gc.IsNil)
+	time.Sleep(5 * time.Millisecond)
+	_, err = store.Get(id)
+	c.Assert(err, gc.Equals, bakery.ErrNotFound)
+}
+
+// TestNewMacaroonRejectsIdWithGeneratedRootKey checks the fix for the
+// regression where a caller-supplied id combined with a
+// service-generated root key caused the macaroon id and the store's
+// key id to diverge, making the macaroon unverifiable: NewMacaroon now
+// rejects the id outright instead of silently discarding the store's
+// own key id.
+func (s *RootKeyStoreSuite) TestNewMacaroonRejectsIdWithGeneratedRootKey(c *gc.C) {
+	service, err := bakery.NewService(bakery.NewServiceParams{Location: "loc"})
+	c.Assert(err, gc.IsNil)
+	_, err = service.NewMacaroon("my-id", nil, nil)
+	c.Assert(err, gc.ErrorMatches, "cannot use a caller-specified macaroon id with a service-generated root key")
+}
+
+func (s *RootKeyStoreSuite) TestNewMacaroonWithGeneratedRootKeyIsVerifiable(c *gc.C) {
+	service, err := bakery.NewService(bakery.NewServiceParams{Location: "loc"})
+	c.Assert(err, gc.IsNil)
+	m, err := service.NewMacaroon("", nil, nil)
+	c.Assert(err, gc.IsNil)
+
+	req := service.NewRequest(strCompFirstPartyChecker(""))
+	req.AddClientMacaroon(m)
+	c.Assert(req.Check(), gc.IsNil)
+}
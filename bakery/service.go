@@ -0,0 +1,197 @@
+// Package bakery implements the macaroon-based authorization primitives
+// described in the macaroons paper (http://theory.stanford.edu/~ataly/Papers/macaroons.pdf).
+//
+// It allows a Service to mint macaroons with first and third party caveats,
+// and to verify macaroons presented to it against those caveats.
+package bakery
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"gopkg.in/macaroon.v1"
+)
+
+// Caveat represents a condition that must be satisfied for a check to
+// complete successfully. If Location is non-empty, the caveat must be
+// discharged by a third party service at that location, otherwise it is
+// a first party caveat checked directly against the condition string.
+type Caveat struct {
+	Location  string
+	Condition string
+}
+
+// FirstPartyChecker is used to check first party caveats for validity with
+// respect to information currently known to the service.
+type FirstPartyChecker interface {
+	// CheckFirstPartyCaveat checks that the given caveat condition
+	// is met, returning an error if it is not.
+	CheckFirstPartyCaveat(caveat string) error
+}
+
+// ThirdPartyChecker is used to check third party caveats for validity
+// when discharging them. If successful, it returns a (possibly empty)
+// slice of caveats that will be added to the discharge macaroon.
+type ThirdPartyChecker interface {
+	CheckThirdPartyCaveat(caveatId string, caveat string) ([]Caveat, error)
+}
+
+// PublicKeyLocator is used to find the public key for a given third party
+// discharge location.
+type PublicKeyLocator interface {
+	// PublicKeyForLocation returns the public key associated with
+	// the given discharge location.
+	PublicKeyForLocation(loc string) (*PublicKey, error)
+}
+
+// PublicKeyLocatorMap implements PublicKeyLocator for a fixed map from
+// location to public key.
+type PublicKeyLocatorMap map[string]*PublicKey
+
+// PublicKeyForLocation implements PublicKeyLocator.PublicKeyForLocation.
+func (m PublicKeyLocatorMap) PublicKeyForLocation(loc string) (*PublicKey, error) {
+	if key, ok := m[loc]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("public key for location %q not found", loc)
+}
+
+// NewServiceParams holds the parameters for a call to NewService.
+type NewServiceParams struct {
+	// Location will be set as the location of any macaroons
+	// minted by the service.
+	Location string
+
+	// Store holds the RootKeyStore used to mint and look up macaroon
+	// root keys. If this is nil, a new MemRootKeyStore is used,
+	// configured with ExpireAfter and GenerateInterval below.
+	Store RootKeyStore
+
+	// ExpireAfter and GenerateInterval configure the default store
+	// used when Store is nil; they are ignored otherwise. See
+	// MemRootKeyStore for their meaning.
+	ExpireAfter      time.Duration
+	GenerateInterval time.Duration
+
+	// Key is the service's private/public key pair. It is used to
+	// decrypt third party caveats addressed to it. If it is nil,
+	// a new key pair will be generated.
+	Key *KeyPair
+
+	// Locator is used to find the public keys of other third
+	// party services referred to by the service's caveats.
+	Locator PublicKeyLocator
+}
+
+// Service represents a service that can authenticate clients with
+// macaroons, and mint new macaroons.
+type Service struct {
+	location string
+	store    RootKeyStore
+	key      *KeyPair
+	locator  PublicKeyLocator
+}
+
+// NewService returns a new service that can mint macaroons and check
+// caveats against it, as specified by p.
+func NewService(p NewServiceParams) (*Service, error) {
+	if p.Store == nil {
+		p.Store = MemRootKeyStore(p.ExpireAfter, p.GenerateInterval)
+	}
+	if p.Key == nil {
+		key, err := GenerateKey()
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate key: %v", err)
+		}
+		p.Key = key
+	}
+	return &Service{
+		location: p.Location,
+		store:    p.Store,
+		key:      p.Key,
+		locator:  p.Locator,
+	}, nil
+}
+
+// Location returns the service's configured location.
+func (svc *Service) Location() string {
+	return svc.location
+}
+
+// PublicKey returns the public key of the service.
+func (svc *Service) PublicKey() *PublicKey {
+	return &svc.key.Public
+}
+
+// NewMacaroon mints a new macaroon with the given id and caveats. If
+// rootKey is nil, a fresh root key is requested from the service's
+// RootKeyStore and its id is used in place of id, since that is the
+// only id the key can later be looked up by with Request.Check; in
+// that case id must be empty, as the store has no way to index its
+// generated key under a caller-chosen id.
+func (svc *Service) NewMacaroon(id string, rootKey []byte, caveats []Caveat) (*macaroon.Macaroon, error) {
+	if rootKey == nil {
+		if id != "" {
+			return nil, fmt.Errorf("cannot use a caller-specified macaroon id with a service-generated root key")
+		}
+		key, keyId, err := svc.store.RootKey()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get root key: %v", err)
+		}
+		rootKey = key
+		id = keyId
+	} else if id == "" {
+		idBytes, err := randomBytes(24)
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate macaroon id: %v", err)
+		}
+		id = base64.RawURLEncoding.EncodeToString(idBytes)
+	}
+	m, err := macaroon.New(rootKey, id, svc.location)
+	if err != nil {
+		return nil, fmt.Errorf("cannot bake macaroon: %v", err)
+	}
+	for _, cav := range caveats {
+		if err := svc.AddCaveat(m, cav); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// AddCaveat adds a caveat to the given macaroon. If cav.Location is
+// non-empty, it adds a third party caveat, encrypted to the public key
+// of that location, as found through the service's PublicKeyLocator.
+// Otherwise it adds a first party caveat with the given condition.
+func (svc *Service) AddCaveat(m *macaroon.Macaroon, cav Caveat) error {
+	if cav.Location == "" {
+		m.AddFirstPartyCaveat(cav.Condition)
+		return nil
+	}
+	if svc.locator == nil {
+		return fmt.Errorf("no public key locator configured for third party caveats")
+	}
+	thirdPartyKey, err := svc.locator.PublicKeyForLocation(cav.Location)
+	if err != nil {
+		return fmt.Errorf("cannot find public key for location %q: %v", cav.Location, err)
+	}
+	rootKey, err := randomBytes(24)
+	if err != nil {
+		return fmt.Errorf("cannot generate third party root key: %v", err)
+	}
+	id, err := encodeCaveatId(rootKey, cav.Condition, thirdPartyKey, &svc.key.Public, &svc.key.Private)
+	if err != nil {
+		return fmt.Errorf("cannot encode caveat id: %v", err)
+	}
+	return m.AddThirdPartyCaveat(rootKey, id, cav.Location)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
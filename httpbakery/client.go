@@ -0,0 +1,197 @@
+package httpbakery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+	"gopkg.in/macaroon.v1"
+)
+
+// Client holds the context for making HTTP requests that automatically
+// acquire discharge macaroons in response to ErrDischargeRequired
+// errors, and persists the resulting macaroons as cookies so that
+// subsequent requests do not need to discharge again.
+type Client struct {
+	// Client is used to make the underlying HTTP requests. If it is
+	// nil, http.DefaultClient is used. Its Jar, if any, is used to
+	// persist macaroon cookies between requests.
+	Client *http.Client
+
+	// VisitWebPage is called with the VisitURL of an
+	// InteractionRequiredError when a discharger reports that a
+	// caveat cannot be discharged without the user first completing
+	// some interactive step, such as logging in. If it is nil, such
+	// discharges fail with the InteractionRequiredError rather than
+	// being retried.
+	VisitWebPage func(url string) error
+}
+
+// NewClient returns a new Client that uses a fresh cookie jar to
+// persist macaroons between requests.
+func NewClient() *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		Client: &http.Client{Jar: jar},
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return http.DefaultClient
+}
+
+// Do sends req, acquiring and retrying with any discharge macaroons
+// required to satisfy third party caveats found in an
+// ErrDischargeRequired response, and persists the resulting macaroons
+// as cookies in the client's cookie jar.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read request body: %v", err)
+		}
+		body = data
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	client := c.httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respErr, err := ErrorFromResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if respErr == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	discharges, err := bakery.DischargeAll(respErr.Macaroon, func(firstPartyLocation string, cav macaroon.Caveat) (*macaroon.Macaroon, error) {
+		return c.dischargeMacaroon(cav.Location, cav.Id)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot discharge: %v", err)
+	}
+
+	newReq, err := http.NewRequest(req.Method, req.URL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		newReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		newReq.ContentLength = int64(len(body))
+	}
+	newReq.Header = req.Header
+	if err := c.setCookie(newReq, respErr.Macaroon); err != nil {
+		return nil, err
+	}
+	for _, d := range discharges {
+		if err := c.setCookie(newReq, d); err != nil {
+			return nil, err
+		}
+	}
+	return client.Do(newReq)
+}
+
+// DischargeAll is the httpbakery counterpart of bakery.DischargeAll: it
+// obtains discharge macaroons for all the third party caveats in m by
+// contacting the discharger named in each caveat's location over HTTP,
+// using c to do so. If a discharge requires interaction, c.VisitWebPage
+// is invoked with the VisitURL and the result is awaited on the WaitURL
+// before continuing.
+func DischargeAll(c *Client, m *macaroon.Macaroon) ([]*macaroon.Macaroon, error) {
+	if c == nil {
+		c = NewClient()
+	}
+	return bakery.DischargeAll(m, func(firstPartyLocation string, cav macaroon.Caveat) (*macaroon.Macaroon, error) {
+		return c.dischargeMacaroon(cav.Location, cav.Id)
+	})
+}
+
+// setCookie stores m as a cookie on req's URL, persisting it into the
+// client's cookie jar if it has one, and adding it directly to req so
+// the retried request carries it immediately.
+func (c *Client) setCookie(req *http.Request, m *macaroon.Macaroon) error {
+	data, err := m.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("cannot marshal macaroon: %v", err)
+	}
+	cookie := &http.Cookie{
+		Name:  macaroonCookieName(m.Id()),
+		Value: encodeCookieValue(data),
+		Path:  "/",
+	}
+	req.AddCookie(cookie)
+	if client := c.Client; client != nil && client.Jar != nil {
+		client.Jar.SetCookies(req.URL, []*http.Cookie{cookie})
+	}
+	return nil
+}
+
+// dischargeMacaroon obtains a discharge macaroon for the third party
+// caveat with the given id from the discharger running at loc. If the
+// discharger reports that interaction is required to complete it, and
+// c.VisitWebPage is set, dischargeMacaroon directs the user there and
+// then polls the returned WaitURL until the discharge is ready.
+func (c *Client) dischargeMacaroon(loc string, id string) (*macaroon.Macaroon, error) {
+	client := c.httpClient()
+	resp, err := client.PostForm(loc+DischargeHandlerPath, url.Values{"id": {id}})
+	if err != nil {
+		return nil, fmt.Errorf("cannot contact discharger at %q: %v", loc, err)
+	}
+	defer resp.Body.Close()
+	if ierr, err := interactionRequiredFromResponse(resp); err != nil {
+		return nil, err
+	} else if ierr != nil {
+		if c.VisitWebPage == nil {
+			return nil, ierr
+		}
+		if err := c.VisitWebPage(ierr.VisitURL); err != nil {
+			return nil, fmt.Errorf("cannot visit %q: %v", ierr.VisitURL, err)
+		}
+		return c.waitForDischarge(ierr.WaitURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discharger at %q returned status %v", loc, resp.Status)
+	}
+	return decodeMacaroonResponse(resp, loc)
+}
+
+// waitForDischarge polls waitURL, which blocks server-side until the
+// deferred discharge it refers to has completed, and returns the
+// resulting discharge macaroon.
+func (c *Client) waitForDischarge(waitURL string) (*macaroon.Macaroon, error) {
+	resp, err := c.httpClient().Get(waitURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot wait for discharge at %q: %v", waitURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("wait for discharge at %q returned status %v", waitURL, resp.Status)
+	}
+	return decodeMacaroonResponse(resp, waitURL)
+}
+
+func decodeMacaroonResponse(resp *http.Response, loc string) (*macaroon.Macaroon, error) {
+	var body struct {
+		Macaroon *macaroon.Macaroon
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal discharge response from %q: %v", loc, err)
+	}
+	if body.Macaroon == nil {
+		return nil, fmt.Errorf("discharger at %q did not return a macaroon", loc)
+	}
+	return body.Macaroon, nil
+}
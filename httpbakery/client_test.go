@@ -0,0 +1,118 @@
+package httpbakery_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+	"gopkg.in/macaroon-bakery.v0/httpbakery"
+)
+
+type ClientSuite struct{}
+
+var _ = gc.Suite(&ClientSuite{})
+
+// TestDischargeFlowAndCookiePersistence exercises the full 407
+// discharge-required round trip: a first request fails, Client.Do
+// fetches a discharge macaroon from the third party and retries, and
+// a subsequent request on the same Client succeeds immediately because
+// the resulting macaroons were persisted as cookies.
+func (s *ClientSuite) TestDischargeFlowAndCookiePersistence(c *gc.C) {
+	asKey, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+	as, err := httpbakery.NewService(bakery.NewServiceParams{
+		Location: "as-loc",
+		Key:      asKey,
+	})
+	c.Assert(err, gc.IsNil)
+	asMux := http.NewServeMux()
+	asServer := httptest.NewServer(asMux)
+	defer asServer.Close()
+	httpbakery.NewDischarger(as, "", asMux, strCompThirdPartyChecker("user==bob"))
+
+	ts, err := httpbakery.NewService(bakery.NewServiceParams{
+		Location: "ts-loc",
+		Locator:  bakery.PublicKeyLocatorMap{asServer.URL: &asKey.Public},
+	})
+	c.Assert(err, gc.IsNil)
+
+	requestCount := 0
+	tsMux := http.NewServeMux()
+	tsMux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		requestCount++
+		breq := ts.NewRequest(req, strCompFirstPartyChecker(""))
+		if err := breq.Check(); err != nil {
+			m, merr := ts.NewMacaroon("", nil, []bakery.Caveat{
+				{Location: asServer.URL, Condition: "user==bob"},
+			})
+			c.Assert(merr, gc.IsNil)
+			httpbakery.WriteDischargeRequiredError(w, m, err.Error())
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		c.Assert(err, gc.IsNil)
+		w.Write(body)
+	})
+	tsServer := httptest.NewServer(tsMux)
+	defer tsServer.Close()
+
+	client := httpbakery.NewClient()
+
+	req, err := http.NewRequest("POST", tsServer.URL+"/", strings.NewReader("hello"))
+	c.Assert(err, gc.IsNil)
+	resp, err := client.Do(req)
+	c.Assert(err, gc.IsNil)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(body), gc.Equals, "hello")
+	c.Assert(requestCount, gc.Equals, 2)
+
+	req2, err := http.NewRequest("POST", tsServer.URL+"/", strings.NewReader("again"))
+	c.Assert(err, gc.IsNil)
+	resp2, err := client.Do(req2)
+	c.Assert(err, gc.IsNil)
+	body2, err := ioutil.ReadAll(resp2.Body)
+	resp2.Body.Close()
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(body2), gc.Equals, "again")
+	c.Assert(requestCount, gc.Equals, 3)
+}
+
+func (s *ClientSuite) TestNonDischargeErrorIsReturnedUnchanged(c *gc.C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := httpbakery.NewClient()
+	req, err := http.NewRequest("GET", server.URL, nil)
+	c.Assert(err, gc.IsNil)
+	resp, err := client.Do(req)
+	c.Assert(err, gc.IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+}
+
+type strCompFirstPartyChecker string
+
+func (c strCompFirstPartyChecker) CheckFirstPartyCaveat(caveat string) error {
+	if caveat != string(c) {
+		return fmt.Errorf("%v doesn't match %s", caveat, c)
+	}
+	return nil
+}
+
+type strCompThirdPartyChecker string
+
+func (c strCompThirdPartyChecker) CheckThirdPartyCaveat(caveatId string, caveat string) ([]bakery.Caveat, error) {
+	if caveat != string(c) {
+		return nil, fmt.Errorf("%v doesn't match %s", caveat, c)
+	}
+	return nil, nil
+}
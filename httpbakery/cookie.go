@@ -0,0 +1,14 @@
+package httpbakery
+
+import "encoding/base64"
+
+// encodeCookieValue encodes data for storage in a cookie value, which
+// must be valid as a token in an HTTP header.
+func encodeCookieValue(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCookieValue reverses encodeCookieValue.
+func decodeCookieValue(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(value)
+}
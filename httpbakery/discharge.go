@@ -0,0 +1,84 @@
+package httpbakery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+	"gopkg.in/macaroon.v1"
+)
+
+// DischargeHandlerPath is the path, relative to the prefix passed to
+// AddDischargeHandler, at which discharge macaroons are obtained.
+const DischargeHandlerPath = "/discharge"
+
+// PublicKeyHandlerPath is the path, relative to the prefix passed to
+// AddDischargeHandler, at which the service's public key is published.
+const PublicKeyHandlerPath = "/publickey"
+
+// AddDischargeHandler adds handlers to mux, under the given path
+// prefix, that will handle the standard macaroon discharge protocol
+// for third party caveats addressed to svc. The checker is used to
+// check the conditions of caveats presented for discharge.
+func (svc *Service) AddDischargeHandler(path string, mux *http.ServeMux, checker bakery.ThirdPartyChecker) {
+	mux.HandleFunc(path+DischargeHandlerPath, func(w http.ResponseWriter, req *http.Request) {
+		dischargeHandler(svc, checker, w, req)
+	})
+	mux.HandleFunc(path+PublicKeyHandlerPath, func(w http.ResponseWriter, req *http.Request) {
+		publicKeyHandler(svc, w, req)
+	})
+}
+
+func dischargeHandler(svc *Service, checker bakery.ThirdPartyChecker, w http.ResponseWriter, req *http.Request) {
+	id, ok := formId(w, req)
+	if !ok {
+		return
+	}
+	m, err := svc.Discharge(checker, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot discharge: %v", err), http.StatusForbidden)
+		return
+	}
+	writeDischargeResponse(w, m)
+}
+
+func publicKeyHandler(svc *Service, w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		PublicKey *bakery.PublicKey
+	}{svc.PublicKey()})
+}
+
+// formId extracts the "id" form value identifying the caveat to
+// discharge from req, writing an error response and returning ok=false
+// if it is missing or the form cannot be parsed.
+func formId(w http.ResponseWriter, req *http.Request) (id string, ok bool) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, fmt.Sprintf("cannot parse form: %v", err), http.StatusBadRequest)
+		return "", false
+	}
+	id = req.Form.Get("id")
+	if id == "" {
+		http.Error(w, "no id provided for discharge", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+// writeDischargeResponse writes the standard successful discharge
+// response body, carrying the discharge macaroon m.
+func writeDischargeResponse(w http.ResponseWriter, m *macaroon.Macaroon) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Macaroon *macaroon.Macaroon
+	}{m})
+}
+
+// writeJSONError writes body as a JSON response with the given status
+// code.
+func writeJSONError(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,143 @@
+package httpbakery
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+)
+
+// WaitHandlerPath is the path, relative to the prefix passed to
+// NewDischarger, at which clients poll for the result of a deferred
+// discharge.
+const WaitHandlerPath = "/wait"
+
+// ErrInteractionRequired should be returned by a ThirdPartyChecker's
+// CheckThirdPartyCaveat to signal that the discharge cannot be
+// completed immediately and that the user must first complete some
+// interactive step, such as logging in. A Discharger that receives
+// this error places the caveat in its rendezvous and reports an
+// InteractionRequiredError to the client instead of discharging it
+// straight away.
+var ErrInteractionRequired = fmt.Errorf("interaction required")
+
+// Discharger serves the discharge protocol for a bakery.Service,
+// supporting both immediate discharge, via checker, and deferred
+// discharge for identity-provider style services: when checker returns
+// ErrInteractionRequired, the caveat is held in a rendezvous until the
+// embedding service calls Complete for its wait id, typically once its
+// own login handler (served at the InteractionRequiredError's
+// VisitURL) has authenticated the user.
+type Discharger struct {
+	svc        *Service
+	prefix     string
+	checker    bakery.ThirdPartyChecker
+	rendezvous *rendezvous
+}
+
+// NewDischarger returns a Discharger for svc, and registers its
+// handlers on mux under the given path prefix: the standard discharge
+// and publickey endpoints, plus a wait endpoint used to poll for
+// deferred discharges.
+func NewDischarger(svc *Service, prefix string, mux *http.ServeMux, checker bakery.ThirdPartyChecker) *Discharger {
+	d := &Discharger{
+		svc:        svc,
+		prefix:     prefix,
+		checker:    checker,
+		rendezvous: newRendezvous(),
+	}
+	mux.HandleFunc(prefix+DischargeHandlerPath, d.serveDischarge)
+	mux.HandleFunc(prefix+WaitHandlerPath, d.serveWait)
+	mux.HandleFunc(prefix+PublicKeyHandlerPath, func(w http.ResponseWriter, req *http.Request) {
+		publicKeyHandler(svc, w, req)
+	})
+	return d
+}
+
+// Complete finishes a deferred discharge previously reported via an
+// InteractionRequiredError under waitId, allowing the client waiting on
+// its WaitURL to proceed. caveats are added to the discharge macaroon
+// as for a normal ThirdPartyChecker; if err is non-nil, discharge fails
+// and the waiting client is told so instead.
+func (d *Discharger) Complete(waitId string, caveats []bakery.Caveat, err error) error {
+	return d.rendezvous.complete(waitId, caveats, err)
+}
+
+func (d *Discharger) serveDischarge(w http.ResponseWriter, req *http.Request) {
+	id, ok := formId(w, req)
+	if !ok {
+		return
+	}
+	m, err := d.svc.Discharge(d.checker, id)
+	if err == ErrInteractionRequired {
+		waitId, perr := d.rendezvous.place(id)
+		if perr != nil {
+			http.Error(w, perr.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeInteractionRequiredError(w, requestOrigin(req)+d.prefix, waitId)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot discharge: %v", err), http.StatusForbidden)
+		return
+	}
+	writeDischargeResponse(w, m)
+}
+
+func (d *Discharger) serveWait(w http.ResponseWriter, req *http.Request) {
+	waitId := req.URL.Query().Get("waitid")
+	if waitId == "" {
+		http.Error(w, "no waitid provided", http.StatusBadRequest)
+		return
+	}
+	item, err := d.rendezvous.wait(waitId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if item.err != nil {
+		http.Error(w, fmt.Sprintf("cannot discharge: %v", item.err), http.StatusForbidden)
+		return
+	}
+	m, err := d.svc.Discharge(staticThirdPartyChecker(item.caveats), item.caveatId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot discharge: %v", err), http.StatusForbidden)
+		return
+	}
+	writeDischargeResponse(w, m)
+}
+
+// staticThirdPartyChecker is a ThirdPartyChecker that always returns a
+// fixed set of caveats, used to complete a discharge that has already
+// been approved via Discharger.Complete without re-running the
+// original checker.
+type staticThirdPartyChecker []bakery.Caveat
+
+func (c staticThirdPartyChecker) CheckThirdPartyCaveat(caveatId, caveat string) ([]bakery.Caveat, error) {
+	return []bakery.Caveat(c), nil
+}
+
+// writeInteractionRequiredError writes an InteractionRequiredError whose
+// VisitURL and WaitURL are resolved against baseURL (the discharger's
+// origin and path prefix), so that they are absolute URLs the client
+// can fetch directly even though it first reached the discharger at a
+// different origin than its own.
+func writeInteractionRequiredError(w http.ResponseWriter, baseURL, waitId string) {
+	writeJSONError(w, InteractionRequiredStatusCode, &InteractionRequiredError{
+		Message:  "interaction required",
+		VisitURL: fmt.Sprintf("%s/visit?waitid=%s", baseURL, waitId),
+		WaitURL:  fmt.Sprintf("%s%s?waitid=%s", baseURL, WaitHandlerPath, waitId),
+	})
+}
+
+// requestOrigin returns the scheme and host that req was received on,
+// so that a relative path can be turned into an absolute URL usable by
+// a client that did not itself choose that origin.
+func requestOrigin(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host
+}
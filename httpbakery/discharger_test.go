@@ -0,0 +1,144 @@
+package httpbakery_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+	"gopkg.in/macaroon-bakery.v0/httpbakery"
+)
+
+type DischargerSuite struct{}
+
+var _ = gc.Suite(&DischargerSuite{})
+
+type interactiveThirdPartyChecker struct{}
+
+func (interactiveThirdPartyChecker) CheckThirdPartyCaveat(caveatId, caveat string) ([]bakery.Caveat, error) {
+	return nil, httpbakery.ErrInteractionRequired
+}
+
+// TestInteractiveDischarge checks the visit/wait rendezvous used when a
+// discharge cannot be completed immediately: the discharger reports an
+// InteractionRequiredError, the client is sent to VisitURL, and the
+// discharge only completes once Discharger.Complete is called for the
+// corresponding wait id, at which point the client's pending wait on
+// WaitURL returns the discharge macaroon.
+func (s *DischargerSuite) TestInteractiveDischarge(c *gc.C) {
+	asKey, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+	as, err := httpbakery.NewService(bakery.NewServiceParams{
+		Location: "as-loc",
+		Key:      asKey,
+	})
+	c.Assert(err, gc.IsNil)
+
+	asMux := http.NewServeMux()
+	asServer := httptest.NewServer(asMux)
+	defer asServer.Close()
+	discharger := httpbakery.NewDischarger(as, "", asMux, interactiveThirdPartyChecker{})
+
+	ts, err := httpbakery.NewService(bakery.NewServiceParams{
+		Location: "ts-loc",
+		Locator:  bakery.PublicKeyLocatorMap{asServer.URL: &asKey.Public},
+	})
+	c.Assert(err, gc.IsNil)
+	m, err := ts.NewMacaroon("", nil, []bakery.Caveat{
+		{Location: asServer.URL, Condition: "user==bob"},
+	})
+	c.Assert(err, gc.IsNil)
+
+	var visitedURL string
+	client := &httpbakery.Client{
+		VisitWebPage: func(visitURL string) error {
+			visitedURL = visitURL
+			u, err := url.Parse(visitURL)
+			c.Assert(err, gc.IsNil)
+			waitId := u.Query().Get("waitid")
+			go discharger.Complete(waitId, nil, nil)
+			return nil
+		},
+	}
+
+	discharges, err := httpbakery.DischargeAll(client, m)
+	c.Assert(err, gc.IsNil)
+	c.Assert(discharges, gc.HasLen, 1)
+	c.Assert(visitedURL, gc.Matches, asServer.URL+"/visit\\?waitid=.+")
+}
+
+// TestCompleteTwiceFailsInsteadOfPanicking checks the fix for the
+// panic-on-double-complete bug: a retried login callback, or two
+// callers racing on the same wait id, must not close an
+// already-closed done channel.
+func (s *DischargerSuite) TestCompleteTwiceFailsInsteadOfPanicking(c *gc.C) {
+	asKey, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+	as, err := httpbakery.NewService(bakery.NewServiceParams{
+		Location: "as-loc",
+		Key:      asKey,
+	})
+	c.Assert(err, gc.IsNil)
+
+	asMux := http.NewServeMux()
+	asServer := httptest.NewServer(asMux)
+	defer asServer.Close()
+	discharger := httpbakery.NewDischarger(as, "", asMux, interactiveThirdPartyChecker{})
+
+	ts, err := httpbakery.NewService(bakery.NewServiceParams{
+		Location: "ts-loc",
+		Locator:  bakery.PublicKeyLocatorMap{asServer.URL: &asKey.Public},
+	})
+	c.Assert(err, gc.IsNil)
+	m, err := ts.NewMacaroon("", nil, []bakery.Caveat{
+		{Location: asServer.URL, Condition: "user==bob"},
+	})
+	c.Assert(err, gc.IsNil)
+
+	client := &httpbakery.Client{
+		VisitWebPage: func(visitURL string) error {
+			u, err := url.Parse(visitURL)
+			c.Assert(err, gc.IsNil)
+			waitId := u.Query().Get("waitid")
+			// Simulate a retried login callback completing the
+			// same wait id twice before anyone has waited on it.
+			c.Assert(discharger.Complete(waitId, nil, nil), gc.IsNil)
+			err = discharger.Complete(waitId, nil, nil)
+			c.Assert(err, gc.ErrorMatches, `discharge for wait id ".*" already completed`)
+			return nil
+		},
+	}
+
+	_, err = httpbakery.DischargeAll(client, m)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *DischargerSuite) TestInteractiveDischargeFailsWithoutVisitWebPage(c *gc.C) {
+	asKey, err := bakery.GenerateKey()
+	c.Assert(err, gc.IsNil)
+	as, err := httpbakery.NewService(bakery.NewServiceParams{
+		Location: "as-loc",
+		Key:      asKey,
+	})
+	c.Assert(err, gc.IsNil)
+
+	asMux := http.NewServeMux()
+	asServer := httptest.NewServer(asMux)
+	defer asServer.Close()
+	httpbakery.NewDischarger(as, "", asMux, interactiveThirdPartyChecker{})
+
+	ts, err := httpbakery.NewService(bakery.NewServiceParams{
+		Location: "ts-loc",
+		Locator:  bakery.PublicKeyLocatorMap{asServer.URL: &asKey.Public},
+	})
+	c.Assert(err, gc.IsNil)
+	m, err := ts.NewMacaroon("", nil, []bakery.Caveat{
+		{Location: asServer.URL, Condition: "user==bob"},
+	})
+	c.Assert(err, gc.IsNil)
+
+	_, err = httpbakery.DischargeAll(httpbakery.NewClient(), m)
+	c.Assert(err, gc.ErrorMatches, `cannot get discharge for caveat addressed to ".*": interaction required`)
+}
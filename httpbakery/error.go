@@ -0,0 +1,72 @@
+// Package httpbakery defines a way of using the macaroon-based
+// authorization protocol (see package gopkg.in/macaroon-bakery.v0/bakery)
+// over HTTP, and provides helpers for target services and for clients
+// that are able to perform the associated discharge protocol.
+package httpbakery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/macaroon.v1"
+)
+
+// ErrDischargeRequired is returned to an HTTP client when it must
+// acquire discharge macaroons before it can complete a request. It
+// implements the error interface and is marshaled as the body of a
+// response with status http.StatusProxyAuthRequired (407), which is used
+// in place of 401 because browsers and other HTTP agents treat 401
+// specially.
+type ErrDischargeRequired struct {
+	// Message holds a human readable explanation of the error.
+	Message string `json:"Message"`
+
+	// Macaroon holds the macaroon that the client must discharge in
+	// order to complete the request.
+	Macaroon *macaroon.Macaroon `json:"Info,omitempty"`
+}
+
+// Error implements error.Error.
+func (e *ErrDischargeRequired) Error() string {
+	return e.Message
+}
+
+// ErrDischargeRequiredStatusCode is the HTTP status code used to signal
+// that discharge is required for a macaroon.
+const ErrDischargeRequiredStatusCode = http.StatusProxyAuthRequired
+
+// WriteDischargeRequiredError writes a response to w reporting that the
+// given macaroon must be discharged in order to authorize the request.
+func WriteDischargeRequiredError(w http.ResponseWriter, m *macaroon.Macaroon, why string) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(ErrDischargeRequiredStatusCode)
+	return json.NewEncoder(w).Encode(&ErrDischargeRequired{
+		Message:  why,
+		Macaroon: m,
+	})
+}
+
+// ErrorFromResponse returns the ErrDischargeRequired carried by resp, if
+// resp's status code is ErrDischargeRequiredStatusCode, and nil
+// otherwise.
+func ErrorFromResponse(resp *http.Response) (*ErrDischargeRequired, error) {
+	if resp.StatusCode != ErrDischargeRequiredStatusCode {
+		return nil, nil
+	}
+	var respErr ErrDischargeRequired
+	if err := json.NewDecoder(resp.Body).Decode(&respErr); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal discharge-required response: %v", err)
+	}
+	if respErr.Macaroon == nil {
+		return nil, fmt.Errorf("discharge-required response did not include a macaroon")
+	}
+	return &respErr, nil
+}
+
+// macaroonCookieName returns the name used for the cookie that stores a
+// macaroon under the given id.
+func macaroonCookieName(id string) string {
+	return "macaroon-" + base64.RawURLEncoding.EncodeToString([]byte(id))
+}
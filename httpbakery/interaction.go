@@ -0,0 +1,44 @@
+package httpbakery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// InteractionRequiredStatusCode is the HTTP status used to report that
+// a discharge cannot be completed without some interactive step (for
+// example logging in) on the part of the user.
+const InteractionRequiredStatusCode = http.StatusUnauthorized
+
+// InteractionRequiredError is returned by a discharge endpoint served
+// by a Discharger when the discharge cannot be completed immediately.
+// The client is expected to direct the user to VisitURL and then poll
+// WaitURL, which will block until the discharge is complete.
+type InteractionRequiredError struct {
+	Message  string `json:"Message"`
+	VisitURL string `json:"VisitURL"`
+	WaitURL  string `json:"WaitURL"`
+}
+
+// Error implements error.Error.
+func (e *InteractionRequiredError) Error() string {
+	return e.Message
+}
+
+// interactionRequiredFromResponse returns the InteractionRequiredError
+// carried by resp, if resp's status code is
+// InteractionRequiredStatusCode, and nil otherwise.
+func interactionRequiredFromResponse(resp *http.Response) (*InteractionRequiredError, error) {
+	if resp.StatusCode != InteractionRequiredStatusCode {
+		return nil, nil
+	}
+	var ierr InteractionRequiredError
+	if err := json.NewDecoder(resp.Body).Decode(&ierr); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal interaction-required response: %v", err)
+	}
+	if ierr.VisitURL == "" || ierr.WaitURL == "" {
+		return nil, fmt.Errorf("interaction-required response missing VisitURL or WaitURL")
+	}
+	return &ierr, nil
+}
@@ -0,0 +1,76 @@
+package httpbakery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+)
+
+// PublicKeyRing is a bakery.PublicKeyLocator that lazily fetches and
+// caches the public keys of discharge services it has not seen before,
+// by querying their "<location>/publickey" endpoint (see
+// Service.AddDischargeHandler). This lets a target service accept
+// third party caveats addressed to dischargers it did not know about
+// in advance.
+type PublicKeyRing struct {
+	client *http.Client
+	ring   *bakery.PublicKeyRing
+}
+
+// NewPublicKeyRing returns a PublicKeyRing that uses client to fetch
+// public keys it does not already hold. If client is nil,
+// http.DefaultClient is used.
+func NewPublicKeyRing(client *http.Client) *PublicKeyRing {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &PublicKeyRing{
+		client: client,
+		ring:   bakery.NewPublicKeyRing(),
+	}
+}
+
+// AddPublicKeyForLocation pre-populates the ring's cache, as for
+// bakery.PublicKeyRing.AddPublicKeyForLocation, so that loc's
+// /publickey endpoint need not be queried.
+func (r *PublicKeyRing) AddPublicKeyForLocation(loc string, prefix bool, key *bakery.PublicKey) {
+	r.ring.AddPublicKeyForLocation(loc, prefix, key)
+}
+
+// PublicKeyForLocation implements bakery.PublicKeyLocator.PublicKeyForLocation,
+// fetching and caching the key from loc's /publickey endpoint if it is
+// not already known.
+func (r *PublicKeyRing) PublicKeyForLocation(loc string) (*bakery.PublicKey, error) {
+	if key, err := r.ring.PublicKeyForLocation(loc); err == nil {
+		return key, nil
+	}
+	key, err := r.fetchPublicKey(loc)
+	if err != nil {
+		return nil, err
+	}
+	r.ring.AddPublicKeyForLocation(loc, false, key)
+	return key, nil
+}
+
+func (r *PublicKeyRing) fetchPublicKey(loc string) (*bakery.PublicKey, error) {
+	resp, err := r.client.Get(loc + PublicKeyHandlerPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get public key from %q: %v", loc, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot get public key from %q: %v", loc, resp.Status)
+	}
+	var body struct {
+		PublicKey *bakery.PublicKey
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal public key response from %q: %v", loc, err)
+	}
+	if body.PublicKey == nil {
+		return nil, fmt.Errorf("no public key found in response from %q", loc)
+	}
+	return body.PublicKey, nil
+}
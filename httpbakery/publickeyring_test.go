@@ -0,0 +1,59 @@
+package httpbakery_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+	"gopkg.in/macaroon-bakery.v0/httpbakery"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type PublicKeyRingSuite struct{}
+
+var _ = gc.Suite(&PublicKeyRingSuite{})
+
+func (s *PublicKeyRingSuite) TestFetchesKeyOverHTTP(c *gc.C) {
+	svc, err := httpbakery.NewService(bakery.NewServiceParams{Location: "loc"})
+	c.Assert(err, gc.IsNil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(httpbakery.PublicKeyHandlerPath, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			PublicKey *bakery.PublicKey
+		}{svc.PublicKey()})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ring := httpbakery.NewPublicKeyRing(nil)
+	key, err := ring.PublicKeyForLocation(server.URL)
+	c.Assert(err, gc.IsNil)
+	c.Assert(key, gc.DeepEquals, svc.PublicKey())
+}
+
+func (s *PublicKeyRingSuite) TestCachesPrePopulatedKey(c *gc.C) {
+	svc, err := httpbakery.NewService(bakery.NewServiceParams{Location: "loc"})
+	c.Assert(err, gc.IsNil)
+
+	ring := httpbakery.NewPublicKeyRing(nil)
+	ring.AddPublicKeyForLocation("loc1", false, svc.PublicKey())
+
+	key, err := ring.PublicKeyForLocation("loc1")
+	c.Assert(err, gc.IsNil)
+	c.Assert(key, gc.DeepEquals, svc.PublicKey())
+}
+
+func (s *PublicKeyRingSuite) TestFetchFailsForUnreachableLocation(c *gc.C) {
+	ring := httpbakery.NewPublicKeyRing(nil)
+	_, err := ring.PublicKeyForLocation("http://127.0.0.1:1")
+	c.Assert(err, gc.ErrorMatches, `cannot get public key from .*`)
+}
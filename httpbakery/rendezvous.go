@@ -0,0 +1,110 @@
+package httpbakery
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+)
+
+// rendezvousExpireAfter bounds how long a placed discharge may remain
+// unclaimed before rendezvous forgets it, so that a client that keeps
+// triggering ErrInteractionRequired without ever completing the login
+// (an abandoned interactive login, or a service that never calls
+// Complete) can't grow the discharger's memory unboundedly.
+const rendezvousExpireAfter = 10 * time.Minute
+
+// rendezvous is a server-side meeting place used to implement deferred
+// discharge. A pending caveat is "placed" under a random wait id when
+// it cannot be discharged immediately, and later "completed" (normally
+// by whatever handles the identity provider's own login flow) with the
+// caveats to add to the discharge macaroon, or an error. wait blocks
+// until the matching complete call is made.
+type rendezvous struct {
+	mu    sync.Mutex
+	items map[string]*rendezvousItem
+}
+
+type rendezvousItem struct {
+	caveatId  string
+	done      chan struct{}
+	caveats   []bakery.Caveat
+	err       error
+	placed    time.Time
+	completed bool
+}
+
+func newRendezvous() *rendezvous {
+	return &rendezvous{
+		items: make(map[string]*rendezvousItem),
+	}
+}
+
+func (r *rendezvous) place(caveatId string) (waitId string, err error) {
+	data := make([]byte, 24)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("cannot generate wait id: %v", err)
+	}
+	waitId = base64.RawURLEncoding.EncodeToString(data)
+	now := time.Now()
+	r.mu.Lock()
+	r.items[waitId] = &rendezvousItem{
+		caveatId: caveatId,
+		done:     make(chan struct{}),
+		placed:   now,
+	}
+	r.expireOldItems(now)
+	r.mu.Unlock()
+	return waitId, nil
+}
+
+// complete finishes the pending discharge placed under waitId. It
+// fails, rather than completing the discharge again, if waitId is
+// unknown or has already been completed - Complete may otherwise be
+// called more than once for the same wait id by a retried callback or
+// a race between callers, and closing an already-closed done channel
+// would panic.
+func (r *rendezvous) complete(waitId string, caveats []bakery.Caveat, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.items[waitId]
+	if !ok {
+		return fmt.Errorf("no pending discharge for wait id %q", waitId)
+	}
+	if item.completed {
+		return fmt.Errorf("discharge for wait id %q already completed", waitId)
+	}
+	item.completed = true
+	item.caveats, item.err = caveats, err
+	close(item.done)
+	return nil
+}
+
+// wait blocks until waitId has been completed, then removes it from
+// the rendezvous so it cannot be waited on twice.
+func (r *rendezvous) wait(waitId string) (*rendezvousItem, error) {
+	r.mu.Lock()
+	item, ok := r.items[waitId]
+	r.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no pending discharge for wait id %q", waitId)
+	}
+	<-item.done
+	r.mu.Lock()
+	delete(r.items, waitId)
+	r.mu.Unlock()
+	return item, nil
+}
+
+// expireOldItems removes items placed more than rendezvousExpireAfter
+// ago. Callers must hold r.mu.
+func (r *rendezvous) expireOldItems(now time.Time) {
+	for waitId, item := range r.items {
+		if now.Sub(item.placed) > rendezvousExpireAfter {
+			delete(r.items, waitId)
+		}
+	}
+}
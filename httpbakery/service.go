@@ -0,0 +1,53 @@
+package httpbakery
+
+import (
+	"net/http"
+
+	"gopkg.in/macaroon-bakery.v0/bakery"
+	"gopkg.in/macaroon.v1"
+)
+
+// Service wraps a bakery.Service so that it can easily be used to
+// protect an HTTP server: it can mint macaroons and write HTTP
+// responses that tell the client which discharge macaroons it needs to
+// acquire.
+type Service struct {
+	*bakery.Service
+}
+
+// NewService returns a new Service, initialized with the given
+// parameters, that can be used to authorize and mint macaroons used in
+// HTTP requests.
+func NewService(p bakery.NewServiceParams) (*Service, error) {
+	svc, err := bakery.NewService(p)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{svc}, nil
+}
+
+// NewRequest returns a bakery.Request built from the macaroons found in
+// the cookies of req, checked against checker.
+func (svc *Service) NewRequest(req *http.Request, checker bakery.FirstPartyChecker) *bakery.Request {
+	breq := svc.Service.NewRequest(checker)
+	for _, cookie := range req.Cookies() {
+		data, err := decodeMacaroonCookie(cookie)
+		if err != nil {
+			continue
+		}
+		breq.AddClientMacaroon(data)
+	}
+	return breq
+}
+
+func decodeMacaroonCookie(cookie *http.Cookie) (*macaroon.Macaroon, error) {
+	data, err := decodeCookieValue(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	var m macaroon.Macaroon
+	if err := m.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}